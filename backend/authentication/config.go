@@ -0,0 +1,18 @@
+package authentication
+
+import "time"
+
+// Config holds the settings needed to construct a TokenProvider.
+type Config struct {
+	// PrivateKeyPath is the PEM-encoded RSA or EC private key used to sign
+	// JWTs. Required for JWTProviderType.
+	PrivateKeyPath string
+
+	// Algorithm is the JWT signing algorithm: "RS256" or "ES256". Defaults
+	// to "RS256". Only consulted by JWTProviderType.
+	Algorithm string
+
+	// TokenTTL is how long an issued token remains valid. Defaults to
+	// simpleTokenTTLDefault for both provider types when zero.
+	TokenTTL time.Duration
+}
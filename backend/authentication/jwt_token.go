@@ -0,0 +1,166 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/sensu/sensu-go/types"
+)
+
+// jwtTokenTTLDefault mirrors simpleTokenTTLDefault so the two providers are
+// interchangeable from sensuctl's point of view.
+const jwtTokenTTLDefault = simpleTokenTTLDefault
+
+// jwtClaims are the claims signed into every token this provider issues.
+type jwtClaims struct {
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+	jwt.StandardClaims
+}
+
+// jwtToken issues signed JWTs carrying the caller's username and role
+// names. Unlike simpleToken it keeps no server-side state: validating a
+// token is just a signature and expiry check, which is what lets multiple
+// sensu-backend instances validate tokens without sharing a token store.
+type jwtToken struct {
+	lookup       RoleLookup
+	ttl          time.Duration
+	signingKey   interface{} // *rsa.PrivateKey or *ecdsa.PrivateKey
+	verifyKey    interface{} // *rsa.PublicKey or *ecdsa.PublicKey
+	method       jwt.SigningMethod
+	disabledFlag bool
+	mu           sync.Mutex
+}
+
+func newJWTToken(lookup RoleLookup, cfg Config) (*jwtToken, error) {
+	if cfg.PrivateKeyPath == "" {
+		return nil, fmt.Errorf("authentication: jwt provider requires Config.PrivateKeyPath")
+	}
+
+	keyBytes, err := ioutil.ReadFile(cfg.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("authentication: unable to read jwt private key: %s", err)
+	}
+
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = "RS256"
+	}
+
+	ttl := cfg.TokenTTL
+	if ttl == 0 {
+		ttl = jwtTokenTTLDefault
+	}
+
+	switch algorithm {
+	case "RS256":
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("authentication: invalid RS256 private key: %s", err)
+		}
+		return &jwtToken{
+			lookup:     lookup,
+			ttl:        ttl,
+			signingKey: key,
+			verifyKey:  &key.PublicKey,
+			method:     jwt.SigningMethodRS256,
+		}, nil
+	case "ES256":
+		key, err := jwt.ParseECPrivateKeyFromPEM(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("authentication: invalid ES256 private key: %s", err)
+		}
+		return &jwtToken{
+			lookup:     lookup,
+			ttl:        ttl,
+			signingKey: key,
+			verifyKey:  &key.PublicKey,
+			method:     jwt.SigningMethodES256,
+		}, nil
+	default:
+		return nil, fmt.Errorf("authentication: unsupported jwt algorithm %q", algorithm)
+	}
+}
+
+// Enable lets Assign issue tokens again after Disable. jwtToken has no
+// background work to start.
+func (j *jwtToken) Enable() {
+	j.mu.Lock()
+	j.disabledFlag = false
+	j.mu.Unlock()
+}
+
+// Disable stops Assign from issuing further tokens. Already-issued tokens
+// remain valid until they expire, since jwtToken has no server-side store
+// to invalidate them from.
+func (j *jwtToken) Disable() {
+	j.mu.Lock()
+	j.disabledFlag = true
+	j.mu.Unlock()
+}
+
+// Assign signs and returns a new token for username.
+func (j *jwtToken) Assign(ctx context.Context, username string) (string, error) {
+	j.mu.Lock()
+	disabled := j.disabledFlag
+	j.mu.Unlock()
+	if disabled {
+		return "", fmt.Errorf("authentication: jwt provider is disabled")
+	}
+
+	roles, err := j.lookup(ctx, username)
+	if err != nil {
+		return "", err
+	}
+
+	roleNames := make([]string, len(roles))
+	for i, r := range roles {
+		roleNames[i] = r.Name
+	}
+
+	now := time.Now()
+	claims := jwtClaims{
+		Username: username,
+		Roles:    roleNames,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(j.ttl).Unix(),
+		},
+	}
+
+	token := jwt.NewWithClaims(j.method, claims)
+	return token.SignedString(j.signingKey)
+}
+
+// Info verifies token's signature and expiry and returns the identity it
+// carries. Role objects are reconstructed from the role names in the
+// claims; full role definitions are not re-fetched, so a role deleted
+// after a token was issued still grants access until the token expires.
+func (j *jwtToken) Info(ctx context.Context, token string) (AuthInfo, bool) {
+	claims := &jwtClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != j.method {
+			return nil, fmt.Errorf("authentication: unexpected signing method %v", t.Header["alg"])
+		}
+		return j.verifyKey, nil
+	})
+	if err != nil {
+		return AuthInfo{}, false
+	}
+
+	roles := make([]*types.Role, len(claims.Roles))
+	for i, name := range claims.Roles {
+		roles[i] = &types.Role{Name: name}
+	}
+
+	return AuthInfo{Username: claims.Username, Roles: roles}, true
+}
+
+// Invalidate is a no-op for jwtToken: it has no server-side store to evict
+// the token from. Callers that need immediate revocation should use
+// SimpleProviderType instead.
+func (j *jwtToken) Invalidate(token string) {}
@@ -0,0 +1,147 @@
+package authentication
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+// simpleTokenTTLDefault is how long a simpleToken stays valid after being
+// issued, mirroring etcd auth's default token lifetime.
+const simpleTokenTTLDefault = 300 * time.Second
+
+const simpleTokenSweepInterval = 30 * time.Second
+
+// simpleToken issues random, server-tracked tokens held in a TTL-indexed
+// map. A background sweeper periodically evicts expired entries.
+type simpleToken struct {
+	lookup RoleLookup
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]simpleTokenEntry
+	enabled bool
+	done    chan struct{}
+}
+
+type simpleTokenEntry struct {
+	info    AuthInfo
+	expires time.Time
+}
+
+func newSimpleToken(lookup RoleLookup, ttl time.Duration) *simpleToken {
+	if ttl == 0 {
+		ttl = simpleTokenTTLDefault
+	}
+	return &simpleToken{
+		lookup:  lookup,
+		ttl:     ttl,
+		entries: make(map[string]simpleTokenEntry),
+	}
+}
+
+// Enable starts the background sweeper that evicts expired tokens.
+func (s *simpleToken) Enable() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.enabled {
+		return
+	}
+	s.enabled = true
+	s.done = make(chan struct{})
+	go s.sweep(s.done)
+}
+
+// Disable stops the sweeper and rejects further Assign calls.
+func (s *simpleToken) Disable() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return
+	}
+	s.enabled = false
+	close(s.done)
+}
+
+func (s *simpleToken) sweep(done <-chan struct{}) {
+	ticker := time.NewTicker(simpleTokenSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for tok, entry := range s.entries {
+				if now.After(entry.expires) {
+					delete(s.entries, tok)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Assign issues a new random token for username, valid for the provider's
+// TTL (simpleTokenTTLDefault unless Config.TokenTTL is set). It fails once
+// Disable has been called, until Enable is called again.
+func (s *simpleToken) Assign(ctx context.Context, username string) (string, error) {
+	// done is only set by Enable, so a provider that was never enabled still
+	// issues tokens; one that was enabled and then disabled does not.
+	s.mu.Lock()
+	disabled := !s.enabled && s.done != nil
+	s.mu.Unlock()
+	if disabled {
+		return "", errors.New("authentication: simple token provider is disabled")
+	}
+
+	roles, err := s.lookup(ctx, username)
+	if err != nil {
+		return "", err
+	}
+
+	tok, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.entries[tok] = simpleTokenEntry{
+		info:    AuthInfo{Username: username, Roles: roles},
+		expires: time.Now().Add(s.ttl),
+	}
+	s.mu.Unlock()
+
+	return tok, nil
+}
+
+// Info looks up token and returns its identity, if it exists and hasn't
+// expired.
+func (s *simpleToken) Info(ctx context.Context, token string) (AuthInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[token]
+	if !ok || time.Now().After(entry.expires) {
+		return AuthInfo{}, false
+	}
+	return entry.info, true
+}
+
+// Invalidate removes token immediately, regardless of its remaining TTL.
+func (s *simpleToken) Invalidate(token string) {
+	s.mu.Lock()
+	delete(s.entries, token)
+	s.mu.Unlock()
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/go-semver/semver"
+)
+
+// Capability identifies an etcd server feature EtcdService depends on.
+type Capability int
+
+const (
+	// V3rpcCapability indicates the server speaks the etcd v3 RPC API at all.
+	V3rpcCapability Capability = iota
+	// LeaseCapability indicates the server supports Lease Grant/KeepAlive/Revoke.
+	LeaseCapability
+	// WatchCapability indicates the server supports Watch.
+	WatchCapability
+)
+
+// requiredCapabilities lists what EtcdService needs from the cluster it is
+// constructed against; New refuses to start if any are missing.
+var requiredCapabilities = []Capability{V3rpcCapability, LeaseCapability, WatchCapability}
+
+// capabilityMaps maps an etcd server's "major.minor" version to the
+// capabilities it supports. Modeled on etcd's own
+// etcdserver/api/capability.go. negotiateCapabilities falls back to the 3.3
+// entry for any later version not listed explicitly; anything older than
+// what's listed here has no entry and so is treated as lacking every
+// capability.
+var capabilityMaps = map[string]map[Capability]bool{
+	"3.0": {V3rpcCapability: true, LeaseCapability: true, WatchCapability: true},
+	"3.1": {V3rpcCapability: true, LeaseCapability: true, WatchCapability: true},
+	"3.2": {V3rpcCapability: true, LeaseCapability: true, WatchCapability: true},
+	"3.3": {V3rpcCapability: true, LeaseCapability: true, WatchCapability: true},
+}
+
+// Capabilities returns the etcd server capabilities detected at
+// construction time (and refreshed on watcher reconnect), so callers like
+// pipelined or keepalived can feature-gate - e.g. falling back to the
+// in-memory monitor backend if lease support is absent.
+func (m *EtcdService) Capabilities() map[Capability]bool {
+	m.capMu.Lock()
+	defer m.capMu.Unlock()
+
+	out := make(map[Capability]bool, len(m.capabilities))
+	for k, v := range m.capabilities {
+		out[k] = v
+	}
+	return out
+}
+
+// negotiateCapabilities asks the cluster for its version and resolves it to
+// a capability set, returning an error if the cluster lacks anything this
+// package requires.
+func negotiateCapabilities(ctx context.Context, client *clientv3.Client, timeout time.Duration) (map[Capability]bool, error) {
+	statusCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	endpoints := client.Endpoints()
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("monitor: etcd client has no endpoints to negotiate capabilities with")
+	}
+
+	status, err := client.Maintenance.Status(statusCtx, endpoints[0])
+	if err != nil {
+		return nil, fmt.Errorf("monitor: unable to determine etcd server version: %s", err)
+	}
+
+	v, err := semver.NewVersion(status.Version)
+	if err != nil {
+		return nil, fmt.Errorf("monitor: unable to parse etcd server version %q: %s", status.Version, err)
+	}
+
+	// Versions at or above 3.3 that aren't listed explicitly are assumed to
+	// support everything 3.3 does, since this package has never relied on
+	// anything newer. Anything below that - including any pre-3.0 cluster -
+	// falls through to the lookup below, which misses and leaves caps nil,
+	// so the required-capability check below correctly fails closed instead
+	// of assuming an old or unrecognized cluster can do what we need.
+	var caps map[Capability]bool
+	if v.Major > 3 || (v.Major == 3 && v.Minor >= 3) {
+		caps = capabilityMaps["3.3"]
+	} else {
+		caps = capabilityMaps[fmt.Sprintf("%d.%d", v.Major, v.Minor)]
+	}
+
+	for _, req := range requiredCapabilities {
+		if !caps[req] {
+			return nil, fmt.Errorf("monitor: etcd server %s lacks required capability %d", status.Version, req)
+		}
+	}
+
+	return caps, nil
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+)
+
+// Type identifies which monitor backend implementation to use.
+type Type string
+
+const (
+	// EtcdType backs monitors with etcd leases and watchers. This is the
+	// default and requires Config.Client to be set.
+	EtcdType Type = "etcd"
+	// MemoryType backs monitors with in-process timers. It has no external
+	// dependencies and is suitable for standalone sensu-backend deployments
+	// and for tests.
+	MemoryType Type = "memory"
+	// RedisType backs monitors with Redis key expiry notifications.
+	RedisType Type = "redis"
+)
+
+// Config describes how to construct a monitor Service via New.
+type Config struct {
+	// Type selects the backend implementation (etcd, memory, redis).
+	// The zero value selects EtcdType.
+	Type Type
+
+	// Endpoints is the list of backend addresses. Used by RedisType;
+	// ignored by EtcdType (which takes its client via Client) and
+	// MemoryType.
+	Endpoints []string
+
+	// Client is the etcd client to use when Type is EtcdType.
+	Client *clientv3.Client
+
+	// TTLResolution is the granularity, in seconds, at which TTLs are
+	// checked. No backend consults it: etcd and Redis use native expiry
+	// notifications, and MemoryType runs one timer per monitor.
+	TTLResolution int64
+
+	// RequestTimeout bounds each backend request (e.g. an etcd Get, Grant,
+	// Put, or KeepAliveOnce). Defaults to DefaultRequestTimeout when zero.
+	// Only consulted by EtcdType.
+	RequestTimeout time.Duration
+}
+
+// New dispatches to the monitor backend identified by cfg.Type, wiring up
+// the given handlers. For EtcdType, it negotiates the cluster's capabilities
+// before returning (see EtcdService.Capabilities) and refuses to start if
+// the cluster lacks lease or watch support.
+func New(ctx context.Context, cfg Config, fail FailureHandler, err ErrorHandler) (Service, error) {
+	switch cfg.Type {
+	case "", EtcdType:
+		if cfg.Client == nil {
+			return nil, fmt.Errorf("monitor: etcd backend requires Config.Client")
+		}
+		timeout := cfg.RequestTimeout
+		if timeout == 0 {
+			timeout = DefaultRequestTimeout
+		}
+		return NewEtcdServiceWithCapabilityCheck(ctx, cfg.Client, fail, err, timeout)
+	case MemoryType:
+		return NewMemoryService(fail, err), nil
+	case RedisType:
+		return NewRedisService(cfg.Endpoints, fail, err)
+	default:
+		return nil, fmt.Errorf("monitor: unknown backend type %q", cfg.Type)
+	}
+}
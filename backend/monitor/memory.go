@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sensu/sensu-go/types"
+)
+
+// MemoryService is an in-process monitor service backed by timers. It has
+// no external dependencies, making it suitable for standalone sensu-backend
+// deployments and for tests that would otherwise need embedded etcd.
+type MemoryService struct {
+	mu             sync.Mutex
+	monitors       map[string]*memoryMonitor
+	failureHandler FailureHandler
+	errorHandler   ErrorHandler
+}
+
+type memoryMonitor struct {
+	ttl    int64
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+// NewMemoryService returns a new monitor service backed by in-process timers.
+func NewMemoryService(fail FailureHandler, err ErrorHandler) *MemoryService {
+	return &MemoryService{
+		monitors:       make(map[string]*memoryMonitor),
+		failureHandler: fail,
+		errorHandler:   err,
+	}
+}
+
+// RefreshMonitor starts a new monitor or resets an existing monitor's timer.
+func (m *MemoryService) RefreshMonitor(ctx context.Context, name string, entity *types.Entity, event *types.Event, ttl int64) error {
+	m.mu.Lock()
+	if mon, ok := m.monitors[name]; ok {
+		mon.cancel()
+	}
+
+	monCtx, cancel := context.WithCancel(ctx)
+	mon := &memoryMonitor{ttl: ttl, cancel: cancel}
+	mon.timer = time.AfterFunc(time.Duration(ttl)*time.Second, func() {
+		m.mu.Lock()
+		current, ok := m.monitors[name]
+		// Only fire for the monitor this timer actually belongs to. A
+		// refresh can replace m.monitors[name] with a new *memoryMonitor
+		// between this timer firing and it acquiring m.mu; without the
+		// pointer check below, a stale timer that lost the race with
+		// Stop() would delete the new monitor and call HandleFailure with
+		// this timer's (stale) entity/event instead of just being a no-op.
+		fire := ok && current == mon
+		if fire {
+			delete(m.monitors, name)
+		}
+		m.mu.Unlock()
+		if !fire {
+			// the monitor was refreshed or stopped before it fired
+			return
+		}
+		logger.Infof("monitor timed out, for %s, handling failure", name)
+		if err := m.failureHandler.HandleFailure(entity, event); err != nil {
+			m.errorHandler.HandleError(err)
+		}
+	})
+	m.monitors[name] = mon
+	m.mu.Unlock()
+
+	// stop the timer if the caller's context is cancelled before it fires,
+	// so a refreshed or shut-down monitor doesn't leak a pending timer.
+	go func() {
+		<-monCtx.Done()
+		mon.timer.Stop()
+	}()
+
+	return nil
+}
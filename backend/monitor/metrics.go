@@ -0,0 +1,48 @@
+package monitor
+
+import "sync"
+
+// WatcherMetrics tracks the health of a resilient watchMon loop so it can
+// be surfaced to Prometheus by the caller.
+type WatcherMetrics struct {
+	mu         sync.Mutex
+	reconnects int
+	lastErr    error
+	revision   int64
+}
+
+// Reconnects returns the number of times the watcher has had to reconnect,
+// for any reason (compaction, transient disconnect, etc).
+func (w *WatcherMetrics) Reconnects() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.reconnects
+}
+
+// LastError returns the most recent error the watcher recovered from, or
+// nil if it has never needed to reconnect.
+func (w *WatcherMetrics) LastError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastErr
+}
+
+// Revision returns the etcd revision the watcher is currently watching from.
+func (w *WatcherMetrics) Revision() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.revision
+}
+
+func (w *WatcherMetrics) recordReconnect(err error) {
+	w.mu.Lock()
+	w.reconnects++
+	w.lastErr = err
+	w.mu.Unlock()
+}
+
+func (w *WatcherMetrics) setRevision(rev int64) {
+	w.mu.Lock()
+	w.revision = rev
+	w.mu.Unlock()
+}
@@ -3,19 +3,35 @@ package monitor
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
 	"github.com/coreos/etcd/mvcc/mvccpb"
 	"github.com/sensu/sensu-go/backend/store"
 	"github.com/sensu/sensu-go/types"
 )
 
+// watchBackoffMin and watchBackoffMax bound the exponential backoff used by
+// watchMon between reconnect attempts after a transient watch failure.
+const (
+	watchBackoffMin = 100 * time.Millisecond
+	watchBackoffMax = 5 * time.Second
+)
+
 var (
 	monitorPathPrefix = "monitors"
 	monitorKeyBuilder = store.NewKeyBuilder(monitorPathPrefix)
 )
 
+// DefaultRequestTimeout is the per-request timeout EtcdService uses when
+// Config.RequestTimeout is unset, mirroring etcd's own
+// DefaultRequestTimeout.
+const DefaultRequestTimeout = 5 * time.Second
+
 // Service is the monitors interface.
 type Service interface {
 	// RefreshMonitor starts a new monitor or resets an existing monitor.
@@ -28,6 +44,13 @@ type EtcdService struct {
 	failureHandler FailureHandler
 	errorHandler   ErrorHandler
 	client         *clientv3.Client
+	requestTimeout time.Duration
+
+	watcherMu sync.Mutex
+	watchers  map[string]*WatcherMetrics // monitor key -> its watcher's metrics
+
+	capMu        sync.Mutex
+	capabilities map[Capability]bool
 }
 
 type monitor struct {
@@ -46,19 +69,39 @@ func EtcdFactory(c *clientv3.Client) Factory {
 // Factory is a function that receives handlers and returns a service.
 type Factory func(FailureHandler, ErrorHandler) Service
 
-// NewEtcdService returns a new monitor service backed by Etcd.
+// NewEtcdService returns a new monitor service backed by Etcd. Each request
+// to etcd is bounded by DefaultRequestTimeout; use NewEtcdServiceWithTimeout
+// to configure a different bound.
 func NewEtcdService(client *clientv3.Client, fail FailureHandler, err ErrorHandler) *EtcdService {
+	return NewEtcdServiceWithTimeout(client, fail, err, DefaultRequestTimeout)
+}
+
+// NewEtcdServiceWithTimeout returns a new monitor service backed by Etcd,
+// bounding every etcd RPC it issues to the given per-request timeout.
+func NewEtcdServiceWithTimeout(client *clientv3.Client, fail FailureHandler, err ErrorHandler, timeout time.Duration) *EtcdService {
 	return &EtcdService{
 		client:         client,
 		failureHandler: fail,
 		errorHandler:   err,
+		requestTimeout: timeout,
+		watchers:       make(map[string]*WatcherMetrics),
 	}
 }
 
-// RefreshMonitor checks for the presense of a monitor for a given name.
-// If no monitor exists, one is created. If a monitor exists, its lease ttl is
-// extended. If the monitor's ttl has changed, a new lease is created and the
-// key is updated with that new lease.
+// RefreshMonitor checks for the presence of a monitor for a given name. If
+// no monitor exists, one is created. If a monitor exists and the ttl matches
+// the original ttl of the lease, its lease is extended with keep-alive. If
+// the monitor's ttl has changed, a new lease is created and the key is
+// updated with that new lease.
+//
+// Each monitor keeps its own lease: expiry has to stay tied to that single
+// monitor's own refresh cadence, since a lease shared across monitors would
+// keep every key on it alive as long as any one of them was still being
+// refreshed, masking missed keepalives on the others. A shared-lease-pool
+// RPC-throughput optimization was tried and reverted for exactly this
+// reason (see the commit reverting it); reducing keepalive volume at scale
+// needs a change that tracks each monitor's deadline in-process instead of
+// leaning on etcd's own lease TTL, which is out of scope here.
 func (m *EtcdService) RefreshMonitor(ctx context.Context, name string, entity *types.Entity, event *types.Event, ttl int64) error {
 	key := monitorKeyBuilder.Build(name)
 	// try to get the monitor from the store
@@ -69,13 +112,17 @@ func (m *EtcdService) RefreshMonitor(ctx context.Context, name string, entity *t
 	// if it exists and the ttl matches the original ttl of the lease, extend its
 	// lease with keep-alive.
 	if mon != nil && mon.ttl == ttl {
-		_, kaerr := m.client.KeepAliveOnce(ctx, mon.leaseID)
+		kaCtx, kaCancel := context.WithTimeout(ctx, m.requestTimeout)
+		defer kaCancel()
+		_, kaerr := m.client.KeepAliveOnce(kaCtx, mon.leaseID)
 		return kaerr
 	}
 
 	// If the ttls do not match or the monitor doesn't exist, create a new lease
 	// and do a put on the key with that lease.
-	lease, err := m.client.Grant(ctx, ttl)
+	grantCtx, grantCancel := context.WithTimeout(ctx, m.requestTimeout)
+	defer grantCancel()
+	lease, err := m.client.Grant(grantCtx, ttl)
 	if err != nil {
 		return err
 	}
@@ -86,7 +133,9 @@ func (m *EtcdService) RefreshMonitor(ctx context.Context, name string, entity *t
 		ttl:     ttl,
 	}
 
-	_, err = m.client.Put(ctx, key, fmt.Sprintf("%d", mon.ttl), clientv3.WithLease(lease.ID))
+	putCtx, putCancel := context.WithTimeout(ctx, m.requestTimeout)
+	defer putCancel()
+	_, err = m.client.Put(putCtx, key, fmt.Sprintf("%d", mon.ttl), clientv3.WithLease(lease.ID))
 	if err != nil {
 		return err
 	}
@@ -103,14 +152,62 @@ func (m *EtcdService) RefreshMonitor(ctx context.Context, name string, entity *t
 		logger.Info("shutting down monitor for %s", key)
 	}
 
-	// start the watcher
-	watchMon(ctx, m.client, mon.key, failureFunc, shutdownFunc)
+	// start the watcher, bound to the monitor's lifecycle context rather than
+	// a per-request timeout so it keeps running as long as the caller does.
+	metrics := watchMon(ctx, m, mon.key, failureFunc, shutdownFunc)
+	m.watcherMu.Lock()
+	m.watchers[key] = metrics
+	m.watcherMu.Unlock()
 	return nil
 }
 
+// WatcherMetrics returns the metrics for the most recent watcher this
+// service started for name, or nil if it has started none. Entries are not
+// removed when a watcher stops, so a monitor that has already fired still
+// returns its last watcher's metrics.
+func (m *EtcdService) WatcherMetrics(name string) *WatcherMetrics {
+	key := monitorKeyBuilder.Build(name)
+	m.watcherMu.Lock()
+	defer m.watcherMu.Unlock()
+	return m.watchers[key]
+}
+
+// NewEtcdServiceWithCapabilityCheck behaves like NewEtcdServiceWithTimeout,
+// but first negotiates the cluster's capabilities (see Capabilities) and
+// refuses to start if the cluster lacks lease or watch support, which this
+// service depends on. This is what monitor.New uses for EtcdType.
+func NewEtcdServiceWithCapabilityCheck(ctx context.Context, client *clientv3.Client, fail FailureHandler, err ErrorHandler, timeout time.Duration) (*EtcdService, error) {
+	caps, nerr := negotiateCapabilities(ctx, client, timeout)
+	if nerr != nil {
+		return nil, nerr
+	}
+	svc := NewEtcdServiceWithTimeout(client, fail, err, timeout)
+	svc.capabilities = caps
+	return svc, nil
+}
+
+// recheckCapabilities re-negotiates capabilities with the cluster, passing
+// any error to the error handler (rather than failing) so a transient blip
+// during a rolling upgrade doesn't take the monitor down. watchMon calls it
+// after a watch ends with an error other than compaction, since the
+// cluster's capabilities can change mid-session during a rolling upgrade;
+// compaction recovery and retries of the initial Get do not re-check.
+func (m *EtcdService) recheckCapabilities(ctx context.Context) {
+	caps, err := negotiateCapabilities(ctx, m.client, m.requestTimeout)
+	if err != nil {
+		m.errorHandler.HandleError(err)
+		return
+	}
+	m.capMu.Lock()
+	m.capabilities = caps
+	m.capMu.Unlock()
+}
+
 func (m *EtcdService) getMonitor(ctx context.Context, key string) (*monitor, error) {
 	// try to get the key from the store
-	response, err := m.client.Get(ctx, key)
+	getCtx, cancel := context.WithTimeout(ctx, m.requestTimeout)
+	defer cancel()
+	response, err := m.client.Get(getCtx, key)
 	if err != nil {
 		return nil, err
 	}
@@ -133,23 +230,174 @@ func (m *EtcdService) getMonitor(ctx context.Context, key string) (*monitor, err
 
 // watchMon takes a monitor key and watches for etcd ops. If a DELETE event
 // is witnessed, it calls the provided HandleFailure func. If a PUT event is
-// witnessed, the watcher is stopped.
-func watchMon(ctx context.Context, cli *clientv3.Client, key string, failureHandler func(), shutdownHandler func()) {
-	responseChan := cli.Watch(ctx, key)
+// witnessed, or ctx is cancelled, the watcher is stopped. Unlike a bare
+// clientv3.Watch, watchMon survives compaction and transient disconnects:
+// it resumes from the last seen revision with exponential backoff, and only
+// gives up (firing the failure handler) once it has confirmed via Get that
+// the key itself is actually gone. The returned WatcherMetrics can be polled
+// by the caller to export reconnect counts to Prometheus.
+func watchMon(ctx context.Context, m *EtcdService, key string, failureHandler func(), shutdownHandler func()) *WatcherMetrics {
+	metrics := &WatcherMetrics{}
+	cli := m.client
+
 	go func() {
-		for wresp := range responseChan {
-			for _, ev := range wresp.Events {
-				if ev.Type == mvccpb.DELETE {
+		rev, err := getKeyRevisionRetry(ctx, cli, key, metrics)
+		if err != nil {
+			// ctx was cancelled while we were still retrying the initial
+			// Get; there is no failure to report, just a shutdown.
+			shutdownHandler()
+			return
+		}
+		metrics.setRevision(rev)
+
+		backoff := watchBackoffMin
+		for {
+			select {
+			case <-ctx.Done():
+				shutdownHandler()
+				return
+			default:
+			}
+
+			fired, nextRev, werr := watchOnce(ctx, cli, key, rev, failureHandler, shutdownHandler)
+			if fired {
+				return
+			}
+			if werr == nil {
+				// the watch channel closed without an error, most likely
+				// because ctx was cancelled mid-stream; let the next loop
+				// iteration's ctx.Done() check decide whether to stop.
+				rev = nextRev
+				continue
+			}
+
+			if werr == rpctypes.ErrCompacted {
+				newRev, present, gerr := getKeyRevision(ctx, cli, key)
+				if gerr == nil && !present {
+					logger.Infof("monitor key %s no longer exists after compaction, handling failure", key)
 					failureHandler()
 					return
 				}
-				// if there is a PUT on the key, the lease has been extended,
-				// and we want to kill this watcher to avoid duplicate watchers.
-				if ev.Type == mvccpb.PUT {
-					shutdownHandler()
-					return
+				if gerr == nil {
+					rev = newRev
 				}
+				metrics.recordReconnect(werr)
+				metrics.setRevision(rev)
+				backoff = watchBackoffMin
+				continue
+			}
+
+			rev = nextRev
+			metrics.recordReconnect(werr)
+			metrics.setRevision(rev)
+			// the cluster can be rolling-upgraded out from under a live
+			// watch, so re-check capabilities on every reconnect rather
+			// than trusting what was true at construction time.
+			m.recheckCapabilities(ctx)
+
+			select {
+			case <-ctx.Done():
+				shutdownHandler()
+				return
+			case <-time.After(jitter(backoff)):
+			}
+			backoff *= 2
+			if backoff > watchBackoffMax {
+				backoff = watchBackoffMax
 			}
 		}
 	}()
+
+	return metrics
+}
+
+// watchOnce opens a single watch starting just after rev, confirms the
+// create notification, and streams events until the watch ends (gracefully
+// or with an error). It returns fired=true if a terminal handler (failure or
+// shutdown) was already called, the revision to resume from, and the error
+// (if any) the watch ended with.
+func watchOnce(ctx context.Context, cli *clientv3.Client, key string, rev int64, failureHandler, shutdownHandler func()) (fired bool, nextRev int64, err error) {
+	nextRev = rev
+	wch := cli.Watch(ctx, key, clientv3.WithRev(rev+1), clientv3.WithCreatedNotify())
+
+	created := false
+	for wresp := range wch {
+		if !created {
+			if !wresp.Created {
+				return false, nextRev, fmt.Errorf("monitor: expected created notification for %s, got events", key)
+			}
+			created = true
+			// A watch opened from a compacted revision can come back
+			// Created and already canceled (e.g. with ErrCompacted) in the
+			// same response; check Err() here too, or this falls through
+			// to `continue`, the channel closes, and the caller retries
+			// the same compacted rev forever instead of recovering.
+			if werr := wresp.Err(); werr != nil {
+				return false, nextRev, werr
+			}
+			continue
+		}
+
+		if werr := wresp.Err(); werr != nil {
+			return false, nextRev, werr
+		}
+
+		for _, ev := range wresp.Events {
+			nextRev = ev.Kv.ModRevision
+			if ev.Type == mvccpb.DELETE {
+				failureHandler()
+				return true, nextRev, nil
+			}
+			// if there is a PUT on the key, the lease has been extended,
+			// and we want to kill this watcher to avoid duplicate watchers.
+			if ev.Type == mvccpb.PUT {
+				shutdownHandler()
+				return true, nextRev, nil
+			}
+		}
+	}
+
+	// channel closed without error: treat as a disconnect to reconnect from.
+	return false, nextRev, nil
+}
+
+// getKeyRevision fetches key's current header revision and whether the key
+// is still present.
+func getKeyRevision(ctx context.Context, cli *clientv3.Client, key string) (rev int64, present bool, err error) {
+	resp, err := cli.Get(ctx, key)
+	if err != nil {
+		return 0, false, err
+	}
+	return resp.Header.Revision, len(resp.Kvs) > 0, nil
+}
+
+// getKeyRevisionRetry calls getKeyRevision, retrying with backoff on error
+// instead of giving up on the first failure. watchMon uses this for its
+// starting Get so a transient error there doesn't get mistaken for the key
+// actually being gone and raise a false keepalive alarm.
+func getKeyRevisionRetry(ctx context.Context, cli *clientv3.Client, key string, metrics *WatcherMetrics) (rev int64, err error) {
+	backoff := watchBackoffMin
+	for {
+		rev, _, err = getKeyRevision(ctx, cli, key)
+		if err == nil {
+			return rev, nil
+		}
+		metrics.recordReconnect(err)
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(jitter(backoff)):
+		}
+		backoff *= 2
+		if backoff > watchBackoffMax {
+			backoff = watchBackoffMax
+		}
+	}
+}
+
+// jitter returns d plus up to 20% random jitter, to avoid reconnect storms
+// when many monitors lose their watch at the same time.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
 }
@@ -0,0 +1,67 @@
+// +build integration
+
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHandler records whether HandleFailure was invoked, for use as a
+// FailureHandler/ErrorHandler stand-in in tests.
+type fakeHandler struct {
+	failed chan struct{}
+}
+
+func newFakeHandler() *fakeHandler {
+	return &fakeHandler{failed: make(chan struct{}, 1)}
+}
+
+func (f *fakeHandler) HandleFailure(entity *types.Entity, event *types.Event) error {
+	f.failed <- struct{}{}
+	return nil
+}
+
+func (f *fakeHandler) HandleError(err error) {}
+
+// TestWatchMonSurvivesDisconnect confirms that if the watcher's connection
+// to etcd is severed (simulating a compaction or network blackhole) while a
+// monitor's lease is still ticking down, the failure handler still fires
+// once the lease actually expires, instead of the watcher silently dying.
+func TestWatchMonSurvivesDisconnect(t *testing.T) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{"http://127.0.0.1:2379"},
+		DialTimeout: 5 * time.Second,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	handler := newFakeHandler()
+	svc := NewEtcdService(client, handler, handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entity := types.FixtureEntity("entity1")
+	event := &types.Event{Entity: entity}
+
+	require.NoError(t, svc.RefreshMonitor(ctx, "blackhole-test", entity, event, 2))
+
+	// sever the watcher's underlying connection without cancelling ctx, to
+	// simulate a server restart or network blip mid-watch.
+	client.ActiveConnection().Close()
+
+	select {
+	case <-handler.failed:
+		// the watcher reconnected and the lease's eventual expiry still
+		// triggered the failure handler.
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "failure handler was never called after the connection was severed")
+	}
+}
@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+	"github.com/sensu/sensu-go/types"
+)
+
+// expiredChannel is the keyspace notification channel Redis publishes to
+// when a key with a TTL expires. It requires the server to be configured
+// with `notify-keyspace-events Ex`.
+const expiredChannel = "__keyevent@0__:expired"
+
+// RedisService is a monitor service backed by Redis keyspace notifications.
+// Each monitor sets a key with a TTL; expiry is delivered on expiredChannel
+// and triggers the FailureHandler, the same way a DELETE event does for
+// EtcdService.
+type RedisService struct {
+	client         *goredis.Client
+	failureHandler FailureHandler
+	errorHandler   ErrorHandler
+	pending        sync.Map // key -> *redisMonitor
+}
+
+type redisMonitor struct {
+	entity *types.Entity
+	event  *types.Event
+}
+
+// NewRedisService returns a new monitor service backed by Redis. endpoints
+// must contain at least one address; only the first is used.
+func NewRedisService(endpoints []string, fail FailureHandler, err ErrorHandler) (*RedisService, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("monitor: redis backend requires at least one endpoint")
+	}
+
+	client := goredis.NewClient(&goredis.Options{Addr: endpoints[0]})
+	if _, perr := client.Ping().Result(); perr != nil {
+		return nil, fmt.Errorf("monitor: unable to reach redis at %s: %s", endpoints[0], perr)
+	}
+	if err := checkKeyspaceNotifications(client); err != nil {
+		return nil, err
+	}
+
+	m := &RedisService{
+		client:         client,
+		failureHandler: fail,
+		errorHandler:   err,
+	}
+	m.watchExpirations()
+	return m, nil
+}
+
+// checkKeyspaceNotifications fails fast if the server isn't configured to
+// publish key-expiry events: without `notify-keyspace-events` including both
+// "E" (keyevent) and "x" (expired) classes, watchExpirations's subscription
+// never receives anything, and monitors silently never detect a missed
+// refresh.
+func checkKeyspaceNotifications(client *goredis.Client) error {
+	res, err := client.ConfigGet("notify-keyspace-events").Result()
+	if err != nil {
+		return fmt.Errorf("monitor: unable to read redis notify-keyspace-events config: %s", err)
+	}
+
+	var flags string
+	for i := 0; i+1 < len(res); i += 2 {
+		if res[i] == "notify-keyspace-events" {
+			flags, _ = res[i+1].(string)
+		}
+	}
+
+	hasEvent := false
+	hasExpired := false
+	for _, c := range flags {
+		switch c {
+		case 'A':
+			// "A" aliases the event-type classes, including expired, but
+			// not "K" or "E", which must still be set on their own.
+			hasExpired = true
+		case 'E':
+			hasEvent = true
+		case 'x':
+			hasExpired = true
+		}
+	}
+
+	if !hasEvent || !hasExpired {
+		return fmt.Errorf("monitor: redis requires notify-keyspace-events to include \"Ex\" (got %q), or expired keys will never be reported", flags)
+	}
+	return nil
+}
+
+// watchExpirations subscribes to Redis keyspace expiry notifications and
+// fires the failure handler for any expired key with a pending monitor.
+func (m *RedisService) watchExpirations() {
+	pubsub := m.client.Subscribe(expiredChannel)
+	go func() {
+		for msg := range pubsub.Channel() {
+			key := msg.Payload
+			v, ok := m.pending.Load(key)
+			if !ok {
+				continue
+			}
+			m.pending.Delete(key)
+			mon := v.(*redisMonitor)
+			logger.Infof("monitor timed out, for %s, handling failure", key)
+			if err := m.failureHandler.HandleFailure(mon.entity, mon.event); err != nil {
+				m.errorHandler.HandleError(err)
+			}
+		}
+	}()
+}
+
+// RefreshMonitor starts a new monitor or resets an existing monitor's TTL.
+func (m *RedisService) RefreshMonitor(ctx context.Context, name string, entity *types.Entity, event *types.Event, ttl int64) error {
+	key := monitorKeyBuilder.Build(name)
+	m.pending.Store(key, &redisMonitor{entity: entity, event: event})
+
+	return m.client.WithContext(ctx).Set(key, ttl, time.Duration(ttl)*time.Second).Err()
+}
@@ -0,0 +1,88 @@
+// Package client provides endpoint discovery for cli.SensuCli.Client, so
+// high-availability deployments don't need to hard-code a single backend
+// URL.
+package client
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+// Discoverer resolves a domain to an ordered list of backend endpoint URLs.
+// Tests (and future Kubernetes/Consul-backed discoverers) can substitute a
+// mock implementation.
+type Discoverer interface {
+	Discover(domain string) ([]string, error)
+}
+
+// SRVDiscover is a Discoverer that looks up sensu-go's SRV records,
+// following the same approach as etcd client's SRV discoverer.
+type SRVDiscover struct {
+	// LookupSRV is overridable for tests; defaults to net.LookupSRV.
+	LookupSRV func(service, proto, name string) (string, []*net.SRV, error)
+}
+
+// NewSRVDiscover returns an SRVDiscover backed by net.LookupSRV.
+func NewSRVDiscover() *SRVDiscover {
+	return &SRVDiscover{LookupSRV: net.LookupSRV}
+}
+
+// srvService and srvServiceSSL are the SRV service names sensu-go backends
+// are expected to be published under.
+const (
+	srvService    = "sensu"
+	srvServiceSSL = "sensu-ssl"
+)
+
+// srvTarget pairs an SRV record with the scheme its service name implies,
+// since net.SRV itself carries no scheme.
+type srvTarget struct {
+	rec    *net.SRV
+	scheme string
+}
+
+// Discover looks up both `_sensu._tcp.<domain>` and
+// `_sensu-ssl._tcp.<domain>`, and returns the union of their targets as
+// endpoint URLs, ordered by SRV priority (ascending) then weight
+// (descending).
+func (d *SRVDiscover) Discover(domain string) ([]string, error) {
+	var targets []srvTarget
+
+	if _, srvs, err := d.LookupSRV(srvServiceSSL, "tcp", domain); err == nil {
+		for _, rec := range srvs {
+			targets = append(targets, srvTarget{rec: rec, scheme: "https"})
+		}
+	}
+
+	if _, srvs, err := d.LookupSRV(srvService, "tcp", domain); err == nil {
+		for _, rec := range srvs {
+			targets = append(targets, srvTarget{rec: rec, scheme: "http"})
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("client: no SRV records found for %s", domain)
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		if targets[i].rec.Priority != targets[j].rec.Priority {
+			return targets[i].rec.Priority < targets[j].rec.Priority
+		}
+		return targets[i].rec.Weight > targets[j].rec.Weight
+	})
+
+	endpoints := make([]string, len(targets))
+	for i, t := range targets {
+		endpoints[i] = t.endpointURL()
+	}
+	return endpoints, nil
+}
+
+func (t srvTarget) endpointURL() string {
+	target := t.rec.Target
+	if len(target) > 0 && target[len(target)-1] == '.' {
+		target = target[:len(target)-1]
+	}
+	return fmt.Sprintf("%s://%s:%d", t.scheme, target, t.rec.Port)
+}
@@ -0,0 +1,63 @@
+package client
+
+import "time"
+
+// retryBackoffMin and retryBackoffMax bound the backoff RotatingEndpoints
+// waits between endpoints when a request fails.
+const (
+	retryBackoffMin = 100 * time.Millisecond
+	retryBackoffMax = 2 * time.Second
+)
+
+// ConnectionError marks an error returned from RotatingEndpoints' fn as a
+// connection-level failure (refused, timed out, DNS, TLS, etc.) rather than
+// a response the backend actually sent back. Only errors wrapped this way
+// cause RotatingEndpoints to move on to the next endpoint; a legitimate
+// response error (e.g. a 4xx) means the endpoint was reachable and is
+// returned to the caller immediately instead of being retried elsewhere.
+type ConnectionError struct {
+	Err error
+}
+
+func (e *ConnectionError) Error() string { return e.Err.Error() }
+
+func (e *ConnectionError) Unwrap() error { return e.Err }
+
+// RotatingEndpoints rotates through endpoints, retrying fn against the next
+// endpoint (with exponential backoff between attempts) until it succeeds,
+// fn returns an error that isn't a *ConnectionError, or every endpoint has
+// been tried once. fn is responsible for actually issuing the HTTP call
+// against the given endpoint, and for wrapping connection-level failures in
+// a *ConnectionError so they're distinguishable from the backend's own
+// responses.
+func RotatingEndpoints(endpoints []string, fn func(endpoint string) error) error {
+	var lastErr error
+	backoff := retryBackoffMin
+
+	for i, endpoint := range endpoints {
+		err := fn(endpoint)
+		if err == nil {
+			return nil
+		}
+
+		connErr, ok := err.(*ConnectionError)
+		if !ok {
+			// the endpoint was reachable and responded; that response is
+			// the caller's answer, not a reason to try another endpoint.
+			return err
+		}
+		lastErr = connErr
+
+		if i == len(endpoints)-1 {
+			break
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > retryBackoffMax {
+			backoff = retryBackoffMax
+		}
+	}
+
+	return lastErr
+}
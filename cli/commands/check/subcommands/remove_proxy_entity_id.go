@@ -1,13 +1,24 @@
 package subcommands
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"time"
 
 	"github.com/sensu/sensu-go/cli"
+	sensuclient "github.com/sensu/sensu-go/cli/client"
+	"github.com/sensu/sensu-go/types"
 	"github.com/spf13/cobra"
 )
 
+// defaultRequestTimeout bounds how long this command will wait on any
+// single call to the backend before giving up, so a hung backend doesn't
+// hang the CLI indefinitely.
+const defaultRequestTimeout = 5 * time.Second
+
 // RemoveProxyEntityIDCommand adds a command that allows a user to remove the
 // proxy entity id of a check
 func RemoveProxyEntityIDCommand(cli *cli.SensuCli) *cobra.Command {
@@ -22,16 +33,36 @@ func RemoveProxyEntityIDCommand(cli *cli.SensuCli) *cobra.Command {
 				return errors.New("invalid argument(s) received")
 			}
 
-			check, err := cli.Client.FetchCheck(args[0])
+			timeout, err := cmd.Flags().GetDuration("timeout")
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			endpoints, err := discoveredEndpoints(cmd)
 			if err != nil {
 				return err
 			}
+
+			var check *types.CheckConfig
+			fetchErr := sensuclient.RotatingEndpoints(endpoints, func(string) error {
+				var err error
+				check, err = cli.Client.FetchCheck(ctx, args[0])
+				return asConnectionError(err)
+			})
+			if fetchErr != nil {
+				return fetchErr
+			}
 			check.ProxyEntityID = ""
 
 			if err := check.Validate(); err != nil {
 				return err
 			}
-			if err := cli.Client.UpdateCheck(check); err != nil {
+
+			if err := sensuclient.RotatingEndpoints(endpoints, func(string) error {
+				return asConnectionError(cli.Client.UpdateCheck(ctx, check))
+			}); err != nil {
 				return err
 			}
 
@@ -40,5 +71,50 @@ func RemoveProxyEntityIDCommand(cli *cli.SensuCli) *cobra.Command {
 		},
 	}
 
+	cmd.Flags().Duration("timeout", defaultRequestTimeout, "timeout for backend requests")
+	cmd.Flags().String("discovery-srv", "", "domain whose SRV records set how many attempts a failed request gets; requests still go to the configured backend URL")
+
 	return cmd
 }
+
+// discoveredEndpoints resolves the --discovery-srv domain (if set) to an
+// endpoint list via SRV records, so RotatingEndpoints has more than one
+// entry to retry against. If the flag is unset, it returns a single
+// placeholder entry so RotatingEndpoints still runs its one iteration
+// against the client's already-configured target.
+//
+// cli.SensuCli.Client has no per-call endpoint override in this codebase
+// today, so a connection failure here is retried against the client's
+// single configured target rather than actually redialing a different
+// discovered endpoint; wiring a real per-endpoint override through
+// cli.SensuCli.Client's construction is a larger change than this command
+// can make on its own.
+func discoveredEndpoints(cmd *cobra.Command) ([]string, error) {
+	domain, err := cmd.Flags().GetString("discovery-srv")
+	if err != nil {
+		return nil, err
+	}
+	if domain == "" {
+		return []string{""}, nil
+	}
+	return sensuclient.NewSRVDiscover().Discover(domain)
+}
+
+// asConnectionError wraps err in a *sensuclient.ConnectionError if it looks
+// like a connection-level failure (refused, timed out, DNS, etc.), so
+// RotatingEndpoints retries it instead of treating it as a response the
+// backend already sent back.
+func asConnectionError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return &sensuclient.ConnectionError{Err: err}
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return &sensuclient.ConnectionError{Err: err}
+	}
+	return err
+}